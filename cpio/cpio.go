@@ -0,0 +1,116 @@
+// Package cpio implements a minimal reader for the "newc" cpio format,
+// which is what a macOS .pkg's Payload unpacks to after gunzipping. It only
+// supports reading, and only the newc variant (magic "070701").
+package cpio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+)
+
+const (
+	magic       = "070701"
+	headerLen   = 110
+	trailerName = "TRAILER!!!"
+)
+
+// Header describes a single file record.
+type Header struct {
+	Name string
+	Mode int64
+	Size int64
+}
+
+// Reader reads a cpio newc stream one file at a time, mirroring the
+// Next/Read shape of archive/tar.Reader.
+type Reader struct {
+	r *bufio.Reader
+	// remaining is how many bytes of the current file's data are still
+	// unread; pad is the number of padding bytes that follow that data.
+	remaining int64
+	pad       int64
+}
+
+// NewReader returns a Reader that reads a newc cpio stream from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Next advances to the next file in the archive and returns its header.
+// It returns io.EOF once the TRAILER!!! record is reached.
+func (c *Reader) Next() (*Header, error) {
+	// Skip over any unread data (and padding) from the previous entry
+	if c.remaining > 0 {
+		if _, err := io.CopyN(ioutil.Discard, c.r, c.remaining); err != nil {
+			return nil, err
+		}
+	}
+	if c.pad > 0 {
+		if _, err := io.CopyN(ioutil.Discard, c.r, c.pad); err != nil {
+			return nil, err
+		}
+	}
+	c.remaining, c.pad = 0, 0
+
+	raw := make([]byte, headerLen)
+	if _, err := io.ReadFull(c.r, raw); err != nil {
+		return nil, err
+	}
+	if string(raw[:6]) != magic {
+		return nil, fmt.Errorf("cpio: bad magic %q", raw[:6])
+	}
+
+	field := func(i int) int64 {
+		v, _ := strconv.ParseInt(string(raw[6+i*8:6+i*8+8]), 16, 64)
+		return v
+	}
+	mode := field(1)
+	fileSize := field(6)
+	nameSize := field(11)
+
+	// Name (including its trailing NUL) is padded so header+name lands on
+	// a 4-byte boundary
+	nameBuf := make([]byte, nameSize)
+	if _, err := io.ReadFull(c.r, nameBuf); err != nil {
+		return nil, err
+	}
+	if pad := pad4(headerLen + int(nameSize)); pad > 0 {
+		if _, err := io.CopyN(ioutil.Discard, c.r, int64(pad)); err != nil {
+			return nil, err
+		}
+	}
+	name := string(nameBuf[:len(nameBuf)-1]) // drop trailing NUL
+
+	if name == trailerName {
+		return nil, io.EOF
+	}
+
+	c.remaining = fileSize
+	c.pad = int64(pad4(int(fileSize)))
+
+	return &Header{Name: name, Mode: mode, Size: fileSize}, nil
+}
+
+// Read reads from the current file's data, like archive/tar.Reader.Read.
+func (c *Reader) Read(p []byte) (int, error) {
+	if c.remaining <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > c.remaining {
+		p = p[:c.remaining]
+	}
+	n, err := c.r.Read(p)
+	c.remaining -= int64(n)
+	return n, err
+}
+
+// pad4 returns how many bytes are needed to round n up to a multiple of 4.
+func pad4(n int) int {
+	if rem := n % 4; rem != 0 {
+		return 4 - rem
+	}
+	return 0
+}
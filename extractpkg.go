@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"fyne.io/fyne/widget"
+
+	"github.com/kraxarn/OpenRQinstaller/cpio"
+	"github.com/kraxarn/OpenRQinstaller/xar"
+)
+
+// payloadName is the file inside a .pkg that holds the actual install
+// contents, as a gzip'd cpio archive.
+const payloadName = "Payload"
+
+// modeTypeMask/modeTypeDir are the Unix st_mode type-bits cpio "newc"
+// headers encode Mode as. They don't line up with os.FileMode's own bit
+// layout (os.ModeDir = 1<<31), so a directory entry must be tested this way
+// rather than by casting Mode to os.FileMode and calling IsDir().
+const (
+	modeTypeMask = 0o170000
+	modeTypeDir  = 0o040000
+)
+
+// ExtractPkg unpacks a macOS .pkg (xar archive) to output, without relying
+// on /usr/sbin/installer or Xcode tooling. It finds the pkg's Payload
+// entry, gunzips it, and walks the cpio "newc" archive inside.
+func ExtractPkg(input []byte, output string, progress *widget.ProgressBar) error {
+	reader, err := xar.NewReader(bytes.NewReader(input), int64(len(input)))
+	if err != nil {
+		return err
+	}
+
+	var payload *xar.File
+	for i := range reader.Files {
+		if reader.Files[i].Name == payloadName {
+			payload = &reader.Files[i]
+			break
+		}
+	}
+	if payload == nil {
+		return os.ErrNotExist
+	}
+
+	payloadReader, err := reader.Open(*payload)
+	if err != nil {
+		return err
+	}
+	defer payloadReader.Close()
+
+	gzipReader, err := gzip.NewReader(payloadReader)
+	if err != nil {
+		return err
+	}
+	defer gzipReader.Close()
+
+	// Unlike Extract, the cpio payload is a stream rather than an indexed
+	// archive, so we don't know the total entry count up front to report
+	// fractional progress as we go
+	cpioReader := cpio.NewReader(gzipReader)
+	for {
+		header, err := cpioReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(output, header.Name)
+		if header.Mode&modeTypeMask == modeTypeDir {
+			if err := os.MkdirAll(path, 0700); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return err
+		}
+		data, err := ioutil.ReadAll(cpioReader)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path, data, os.FileMode(header.Mode)); err != nil {
+			return err
+		}
+	}
+
+	progress.SetValue(1)
+	return nil
+}
@@ -3,22 +3,29 @@ package main
 import (
 	"archive/zip"
 	"bytes"
-	"encoding/base64"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"os/exec"
 	"os/user"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"fyne.io/fyne"
 	"fyne.io/fyne/app"
 	"fyne.io/fyne/dialog"
 	"fyne.io/fyne/layout"
 	"fyne.io/fyne/widget"
+
+	"github.com/kraxarn/OpenRQinstaller/registry"
+	"github.com/kraxarn/OpenRQinstaller/release"
+	"github.com/kraxarn/OpenRQinstaller/service"
 )
 
 const appName = "OpenRQ"
@@ -55,8 +62,16 @@ func GetTempPath() string {
 	return fmt.Sprintf(dir, GetUsername())
 }
 
-// Gets the install path
-func GetInstallPath() string {
+// GetInstallPath returns configuredPath if set, otherwise falls back to the
+// platform default derived from GetUsername(). Wizard steps use this to let
+// the user override the default rather than being stuck with it.
+func GetInstallPath(configuredPath string) string {
+	if configuredPath != "" {
+		if !strings.HasSuffix(configuredPath, "/") {
+			configuredPath += "/"
+		}
+		return configuredPath
+	}
 	// Default current directory
 	dir := "%s/%s/"
 	// Try to match platform
@@ -201,22 +216,100 @@ func Copy(input, output string) error {
 	return nil
 }
 
-func Install(progress *widget.ProgressBar, status *widget.Label) error {
+// progressReader wraps an io.Reader and reports how many bytes have been
+// read so far via the given widget.ProgressBar, out of total bytes.
+type progressReader struct {
+	io.Reader
+	progress *widget.ProgressBar
+	total    int64
+	read     int64
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.Reader.Read(b)
+	p.read += int64(n)
+	if p.total > 0 {
+		p.progress.SetValue(float64(p.read) / float64(p.total))
+	}
+	return n, err
+}
+
+// downloadClient is used for the (potentially large) archive download. Its
+// timeout is generous compared to release.Fetch's, since it covers the
+// whole transfer rather than just a small manifest.
+var downloadClient = &http.Client{Timeout: 10 * time.Minute}
+
+// Download fetches target.URL, reporting progress on the given bar as it
+// goes, and returns the raw archive bytes once the SHA-256 digest has been
+// verified against target.SHA256.
+func Download(target *release.Target, progress *widget.ProgressBar) ([]byte, error) {
+	resp, err := downloadClient.Get(target.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download failed: %s", resp.Status)
+	}
+
+	reader := &progressReader{Reader: resp.Body, progress: progress, total: target.Size}
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+	if !strings.EqualFold(digest, target.SHA256) {
+		return nil, fmt.Errorf("download failed: digest mismatch (expected %s, got %s)", target.SHA256, digest)
+	}
+
+	return data, nil
+}
+
+func Install(installPath string, progress *widget.ProgressBar, status *widget.Label) error {
 	// Create install directory if needed
-	if err := os.MkdirAll(GetInstallPath(), 0700); err != nil {
+	if err := os.MkdirAll(GetInstallPath(installPath), 0700); err != nil {
+		return err
+	}
+
+	status.SetText("Fetching release manifest...")
+	manifest, err := release.Fetch("")
+	if err != nil {
+		return err
+	}
+	target, err := manifest.Target()
+	if err != nil {
 		return err
 	}
 
-	data, err := base64.StdEncoding.DecodeString(appData)
+	status.SetText("Downloading...")
+	data, err := Download(target, progress)
 	if err != nil {
 		return err
 	}
 
-	status.SetText(fmt.Sprintf("Installing..."))
-	return Extract(data, GetInstallPath(), progress)
+	progress.SetValue(0)
+	status.SetText("Installing...")
+	extract := Extract
+	if target.Format == "pkg" {
+		extract = ExtractPkg
+	}
+	if err := extract(data, GetInstallPath(installPath), progress); err != nil {
+		return err
+	}
+
+	return release.WriteInstalled(GetInstallPath(installPath), manifest.Version)
 }
 
-func GetShortcutLocation() string {
+// GetShortcutLocation returns configuredLocation if set, otherwise falls
+// back to the platform's start-menu/application-menu default derived from
+// GetUsername().
+func GetShortcutLocation(configuredLocation string) string {
+	if configuredLocation != "" {
+		return configuredLocation
+	}
 	switch runtime.GOOS {
 	case "linux":
 		return fmt.Sprintf("/home/%s/.local/share/applications/%s.desktop",
@@ -229,30 +322,52 @@ func GetShortcutLocation() string {
 	return ""
 }
 
-func CreateShortcut() error {
-	// darwin doesn't use shortcuts
-	if runtime.GOOS == "darwin" {
+// GetDesktopShortcutLocation returns configuredLocation if set, otherwise
+// falls back to the platform's literal Desktop folder.
+func GetDesktopShortcutLocation(configuredLocation string) string {
+	if configuredLocation != "" {
+		return configuredLocation
+	}
+	switch runtime.GOOS {
+	case "linux":
+		return fmt.Sprintf("/home/%s/Desktop/%s.desktop", GetUsername(), strings.ToLower(appName))
+	case "windows":
+		return fmt.Sprintf("C:/Users/%s/Desktop/%s.lnk", GetUsername(), appName)
+	}
+	// Return empty string by default
+	return ""
+}
+
+// fileAssocMimeType is the MIME type OpenRQ's own project files are
+// registered under when "Register file associations" is selected.
+const fileAssocMimeType = "application/x-openrq"
+
+// writeShortcutAt creates a single launcher entry at target, pointing at
+// installPath. It's shared between the desktop shortcut and the start-menu
+// entry, which differ only in where they end up.
+func writeShortcutAt(installPath, target string) error {
+	if len(target) == 0 {
 		return nil
 	}
 	// linux uses a simple desktop file
 	if runtime.GOOS == "linux" {
 		// Create initial shortcut text
 		// (this icon doesn't contain os)
-		content := fmt.Sprintf("[Desktop Entry]\nName=%s\nType=Application\nTerminal=false\nExec=%s\nIcon=%s",
-			appName, GetInstallPath()+appName,
-			fmt.Sprintf("%s%s", GetInstallPath(), appName))
+		content := fmt.Sprintf("[Desktop Entry]\nName=%s\nType=Application\nTerminal=false\nExec=%s\nIcon=%s\nMimeType=%s;",
+			appName, installPath+appName,
+			fmt.Sprintf("%s%s", installPath, appName), fileAssocMimeType)
 		// Try to write to file
-		if err := ioutil.WriteFile(GetShortcutLocation(), []byte(content), 0700); err != nil {
+		if err := ioutil.WriteFile(target, []byte(content), 0700); err != nil {
 			return err
 		}
 		// windows uses annoying binary lnk files
 	} else if runtime.GOOS == "windows" {
 		// We need to create a temporary Visual Basic file and then execute it
-		target := GetInstallPath() + GetExecutableName()
-		icon := GetInstallPath() + appName
+		execTarget := installPath + GetExecutableName()
+		icon := installPath + appName
 		vbs := fmt.Sprintf("Set link = WScript.CreateObject(\"WScript.Shell\").CreateShortcut(\"%s\")\n"+
 			"link.TargetPath = \"%s\"\nlink.IconLocation = \"%s\"\nlink.Description = \"%s\"\nlink.Save",
-			GetShortcutLocation(), target, icon, appName)
+			target, execTarget, icon, appName)
 		// Write vbs to file
 		scriptFile := GetTempPath() + "CreateShortcut.vbs"
 		if err := ioutil.WriteFile(scriptFile, []byte(vbs), 0777); err != nil {
@@ -272,127 +387,337 @@ func CreateShortcut() error {
 	return nil
 }
 
-// Remove application folder and shortcut
-func Uninstall(status *widget.Label) error {
+// CreateShortcut creates the requested launcher entries: a literal Desktop
+// icon, a start-menu/application-menu entry, or both. darwin doesn't use
+// shortcuts.
+func CreateShortcut(installPath string, desktop, startMenu bool) error {
+	if runtime.GOOS == "darwin" {
+		return nil
+	}
+	installPath = GetInstallPath(installPath)
+	if desktop {
+		if err := writeShortcutAt(installPath, GetDesktopShortcutLocation("")); err != nil {
+			return err
+		}
+	}
+	if startMenu {
+		if err := writeShortcutAt(installPath, GetShortcutLocation("")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RegisterFileAssociations makes installPath's own file type open with it by
+// default. On darwin, associations are declared in the app bundle's
+// Info.plist at build time, so there's nothing to do here.
+func RegisterFileAssociations(installPath string) error {
+	installPath = GetInstallPath(installPath)
+	switch runtime.GOOS {
+	case "linux":
+		return registerFileAssociationsLinux(installPath)
+	case "windows":
+		return registerFileAssociationsWindows(installPath)
+	}
+	return nil
+}
+
+// registerFileAssociationsLinux points the desktop environment's default
+// handler for fileAssocMimeType at our start-menu entry. xdg-mime resolves
+// the .desktop file by name out of the standard application directories, so
+// that entry must exist first - even if the user didn't separately check
+// "Create start-menu entry". Uninstall accounts for this and removes it
+// whenever hasFileAssoc is set, regardless of hasStartMenu.
+func registerFileAssociationsLinux(installPath string) error {
+	if err := writeShortcutAt(installPath, GetShortcutLocation("")); err != nil {
+		return err
+	}
+	desktopFile := strings.ToLower(appName) + ".desktop"
+	return exec.Command("xdg-mime", "default", desktopFile, fileAssocMimeType).Run()
+}
+
+// mimeAppsListPath is where xdg-mime stores the "default" association
+// registerFileAssociationsLinux sets.
+func mimeAppsListPath() string {
+	return fmt.Sprintf("/home/%s/.config/mimeapps.list", GetUsername())
+}
+
+// unregisterFileAssociationsLinux undoes registerFileAssociationsLinux's
+// xdg-mime default. xdg-mime itself has no "unset" subcommand, so the only
+// clean way back out is to strip our own line from mimeapps.list directly.
+func unregisterFileAssociationsLinux() error {
+	path := mimeAppsListPath()
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	prefix := fileAssocMimeType + "="
+	var kept []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, prefix) {
+			kept = append(kept, line)
+		}
+	}
+	return ioutil.WriteFile(path, []byte(strings.Join(kept, "\n")), 0600)
+}
+
+// registerFileAssociationsWindows registers the ".rq" extension under the
+// current user, opening it with installPath's executable.
+func registerFileAssociationsWindows(installPath string) error {
+	const ext = ".rq"
+	const progID = "OpenRQ.Document"
+	target := installPath + GetExecutableName()
+
+	if err := exec.Command("reg", "add", `HKCU\Software\Classes\`+ext,
+		"/ve", "/d", progID, "/f").Run(); err != nil {
+		return err
+	}
+	return exec.Command("reg", "add", `HKCU\Software\Classes\`+progID+`\shell\open\command`,
+		"/ve", "/d", fmt.Sprintf("\"%s\" \"%%1\"", target), "/f").Run()
+}
+
+// Remove application folder and whichever optional components this
+// installation actually registered (see registry.Installation.HasComponent).
+// Every side-by-side install has its own service identity (service.Name)
+// and may have made different choices in the components step, so we must
+// never tear down another installation's service or leave its shortcuts
+// behind - nor remove shortcuts/associations this one never created.
+func Uninstall(installPath string, hasDesktopShortcut, hasStartMenu, hasFileAssoc, hasService bool, status *widget.Label) error {
+	if hasService {
+		status.SetText("Removing service...")
+		_ = service.Uninstall(service.Name(GetInstallPath(installPath)))
+	}
 	// Remove application folder
 	status.SetText("Uninstalling application...")
-	if err := os.RemoveAll(GetInstallPath()); err != nil {
+	if err := os.RemoveAll(GetInstallPath(installPath)); err != nil {
 		return err
 	}
-	// Remove shortcut (if needed)
-	status.SetText("Removing shortcut...")
-	shortcut := GetShortcutLocation()
-	if len(shortcut) > 0 {
-		if err := os.Remove(shortcut); err != nil {
+	// Remove shortcuts (if any)
+	status.SetText("Removing shortcuts...")
+	if hasDesktopShortcut {
+		if err := removeIfExists(GetDesktopShortcutLocation("")); err != nil {
 			return err
 		}
 	}
+	// registerFileAssociationsLinux writes the start-menu entry itself as a
+	// side effect (xdg-mime needs it to exist), even if hasStartMenu is
+	// false, so it must be cleaned up under either condition here.
+	if hasStartMenu || (hasFileAssoc && runtime.GOOS == "linux") {
+		if err := removeIfExists(GetShortcutLocation("")); err != nil {
+			return err
+		}
+	}
+	// Undo file associations (if any)
+	if hasFileAssoc {
+		status.SetText("Removing file associations...")
+		if runtime.GOOS == "windows" {
+			_ = exec.Command("reg", "delete", `HKCU\Software\Classes\.rq`, "/f").Run()
+			_ = exec.Command("reg", "delete", `HKCU\Software\Classes\OpenRQ.Document`, "/f").Run()
+		} else if runtime.GOOS == "linux" {
+			_ = unregisterFileAssociationsLinux()
+		}
+	}
 	return nil
 }
 
-// Return row with (un)install options, install is always last item
-func GetButtonContainer(installTapped func(), uninstallTapped func()) *fyne.Container {
-	// Check if directory to install to already exists
-	appInstalled := false
-	if _, err := os.Stat(GetInstallPath()); err == nil {
-		appInstalled = true
-	}
-	// Helper function to toggle button enable/disable
-	var toggleButtons = func(buttons []*widget.Button) {
-		for _, button := range buttons {
-			if button.Disabled() {
-				button.Enable()
-			} else {
-				button.Disable()
-			}
-		}
+// removeIfExists removes path, treating a missing file as success.
+func removeIfExists(path string) error {
+	if len(path) == 0 {
+		return nil
 	}
-	// If not installed, just return an install button
-	if !appInstalled {
-		var button *widget.Button
-		button = widget.NewButton("Install", func() {
-			go func() {
-				// Disable button
-				button.Disable()
-				// Run the main function
-				installTapped()
-				// Enable button again
-				button.Enable()
-			}()
-		})
-		return fyne.NewContainerWithLayout(layout.NewGridLayout(1), button)
-	}
-	// App is not installed, return uninstall and update buttons
-	var buttons []*widget.Button
-	buttons = []*widget.Button{
-		widget.NewButton("Uninstall", func() {
-			go func() {
-				// Disable buttons
-				toggleButtons(buttons)
-				// Run the main function
-				uninstallTapped()
-				// Enable button again
-				toggleButtons(buttons)
-			}()
-		}),
-		widget.NewButton("Update", func() {
-			go func() {
-				// Disable buttons
-				toggleButtons(buttons)
-				// Run the main function
-				installTapped()
-				// Enable button again
-				toggleButtons(buttons)
-			}()
-		}),
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
 	}
-	return fyne.NewContainerWithLayout(layout.NewGridLayout(2), buttons[0], buttons[1])
+	return nil
 }
 
-func GetLayout(parent fyne.Window) fyne.CanvasObject {
-	// Install progress
+// GetButtonContainer renders one row per registered installation, each with
+// its own Uninstall/Update/Repair actions, plus a trailing "Add new
+// installation" button that runs the wizard against a fresh target
+// directory. onChanged is called after any action that may have added or
+// removed an installation, so the caller can rebuild this container.
+func GetButtonContainer(parent fyne.Window, reg *registry.Registry, onChanged func()) *fyne.Container {
+	var rows []fyne.CanvasObject
+	for i := range reg.Installations {
+		rows = append(rows, buildInstallationRow(parent, reg, reg.Installations[i], onChanged))
+	}
+
+	addButton := widget.NewButton("Add new installation", func() {
+		onAddInstallation(parent, reg, onChanged)
+	})
+	rows = append(rows, addButton)
+
+	return fyne.NewContainerWithLayout(layout.NewGridLayout(1), rows...)
+}
+
+// buildInstallationRow renders the path/version summary and action buttons
+// for a single registered installation.
+func buildInstallationRow(parent fyne.Window, reg *registry.Registry, inst registry.Installation, onChanged func()) fyne.CanvasObject {
+	status := widget.NewLabel(fmt.Sprintf("%s (%s)", inst.Path, inst.Version))
 	progress := widget.NewProgressBar()
-	// Status message
-	status := widget.NewLabel("Waiting...")
 
-	// Main layout
-	return widget.NewVBox(
-		// Label with what to install
-		widget.NewGroup(fmt.Sprintf("Welcome to the %s installer!", appName), status),
-		// Install progress
-		progress,
-		// Install button
-		layout.NewSpacer(),
-		//btnInstall,
-		GetButtonContainer(func() {
-			// Install/Update
+	uninstallButton := widget.NewButton("Uninstall", func() {
+		go func() {
 			progress.SetValue(0)
-			// Attempt download
-			if err := Install(progress, status); err != nil {
+			if err := Uninstall(inst.Path,
+				inst.HasComponent("shortcut"), inst.HasComponent("start-menu"),
+				inst.HasComponent("file-associations"), inst.HasComponent("service"), status); err != nil {
 				dialog.ShowError(err, parent)
-				status.SetText("Install failed")
-				// Attempt to create shortcut
-			} else if err := CreateShortcut(); err != nil {
+				return
+			}
+			progress.SetValue(1)
+			reg.Remove(inst.Path)
+			if err := reg.Save(); err != nil {
 				dialog.ShowError(err, parent)
-				status.SetText("Shortcut creation failed")
-			} else {
-				progress.SetValue(1)
-				status.SetText("Installation successful!")
 			}
-		}, func() {
-			// Uninstall
+			onChanged()
+		}()
+	})
+
+	updateButton := widget.NewButton("Update", func() {
+		go func() {
 			progress.SetValue(0)
-			if err := Uninstall(status); err != nil {
+			if err := Install(inst.Path, progress, status); err != nil {
 				dialog.ShowError(err, parent)
-				status.SetText("Uninstall failed")
-			} else {
-				progress.SetValue(1)
-				status.SetText("Uninstall successful")
+				return
 			}
+			onChanged()
+		}()
+	})
+	// Checking the manifest is a blocking network call, so don't do it on
+	// the path that builds this row - GetLayout runs synchronously before
+	// the window is even shown, and this runs once per registered
+	// installation. Disable Update until the check resolves in the
+	// background instead.
+	updateButton.Disable()
+	go func() {
+		if UpdateAvailable(inst.Path) {
+			updateButton.Enable()
+		}
+	}()
+
+	repairButton := widget.NewButton("Repair", func() {
+		go func() {
+			progress.SetValue(0)
+			if err := Install(inst.Path, progress, status); err != nil {
+				dialog.ShowError(err, parent)
+				return
+			}
+			if err := CreateShortcut(inst.Path, inst.HasComponent("shortcut"), inst.HasComponent("start-menu")); err != nil {
+				dialog.ShowError(err, parent)
+				return
+			}
+			if inst.HasComponent("file-associations") {
+				if err := RegisterFileAssociations(inst.Path); err != nil {
+					dialog.ShowError(err, parent)
+				}
+			}
+		}()
+	})
+
+	selectButton := widget.NewButton("Select", func() {
+		reg.Selected = inst.Path
+		if err := reg.Save(); err != nil {
+			dialog.ShowError(err, parent)
+		}
+		onChanged()
+	})
+	if reg.Selected == inst.Path {
+		selectButton.Disable()
+	}
+
+	actions := fyne.NewContainerWithLayout(layout.NewGridLayout(4),
+		uninstallButton, updateButton, repairButton, selectButton)
+	return widget.NewGroup(inst.Path, status, progress, actions)
+}
+
+// onAddInstallation swaps the current window content for a fresh wizard
+// run, returning to the installations list via onChanged once it finishes.
+// The wizard is pre-filled with the platform default path, suffixed with
+// -2, -3, ... until it names a directory that is neither registered nor
+// already on disk.
+func onAddInstallation(parent fyne.Window, reg *registry.Registry, onChanged func()) {
+	base := strings.TrimSuffix(GetInstallPath(""), "/")
+	target := base + "/"
+	for i := 2; ; i++ {
+		if _, err := os.Stat(target); reg.Find(target) == nil && os.IsNotExist(err) {
+			break
+		}
+		target = fmt.Sprintf("%s-%d/", base, i)
+	}
+	parent.SetContent(NewWizard(parent, reg, target, onChanged).Container())
+}
+
+// UpdateAvailable checks the remote manifest against the locally recorded
+// installed.json, returning true if a newer version is available. Any
+// failure to reach the manifest is treated as "no update available" rather
+// than an error, since this only gates the Update button.
+func UpdateAvailable(installPath string) bool {
+	installed, err := release.ReadInstalled(GetInstallPath(installPath))
+	if err != nil || installed.Version == "" {
+		return true
+	}
+	manifest, err := release.Fetch("")
+	if err != nil {
+		return false
+	}
+	return release.CompareVersions(manifest.Version, installed.Version) > 0
+}
+
+// GetLayout returns the content shown in the main window: the registered
+// installations list, or the install wizard directly if there aren't any
+// yet (including a freshly-migrated legacy install).
+func GetLayout(parent fyne.Window) fyne.CanvasObject {
+	reg, err := registry.Load()
+	if err != nil {
+		reg = &registry.Registry{}
+	}
+	migrateLegacyInstall(reg)
+
+	if len(reg.Installations) == 0 {
+		return NewWizard(parent, reg, "", func() {
+			parent.SetContent(GetLayout(parent))
+		}).Container()
+	}
+
+	return widget.NewVBox(
+		widget.NewGroup(fmt.Sprintf("%s installations", appName)),
+		GetButtonContainer(parent, reg, func() {
+			parent.SetContent(GetLayout(parent))
 		}),
 	)
 }
 
+// migrateLegacyInstall seeds reg with the install at the legacy default
+// path if reg has no installations yet and that path exists on disk - this
+// lets installs made before the registry existed show up without
+// reinstalling. A registry that already lists any installation is left as
+// it is.
+func migrateLegacyInstall(reg *registry.Registry) {
+	if len(reg.Installations) > 0 {
+		return
+	}
+	legacyPath := GetInstallPath("")
+	if _, err := os.Stat(legacyPath); err != nil {
+		return
+	}
+
+	version := ""
+	if installed, err := release.ReadInstalled(legacyPath); err == nil {
+		version = installed.Version
+	}
+	reg.Add(registry.Installation{
+		Path:        legacyPath,
+		Version:     version,
+		InstalledAt: time.Now().Format(time.RFC3339),
+	})
+	_ = reg.Save()
+}
+
 func LoadIcon() fyne.Resource {
 	return fyne.NewStaticResource("icon.png", icon)
 }
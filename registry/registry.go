@@ -0,0 +1,147 @@
+// Package registry tracks every installation this tool has performed, so
+// OpenRQ can be installed side-by-side in more than one location. It
+// mirrors the "installed instances" concept from tools like ficsit-cli.
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"runtime"
+)
+
+const fileName = "installations.json"
+
+// Installation records one install this tool has performed.
+type Installation struct {
+	Path        string   `json:"path"`
+	Version     string   `json:"version"`
+	InstalledAt string   `json:"installedAt"`
+	Profile     string   `json:"profile,omitempty"`
+	Components  []string `json:"components,omitempty"`
+}
+
+// Registry is the full set of known installations, plus which one an
+// external launcher should treat as active.
+type Registry struct {
+	Installations []Installation `json:"installations"`
+	Selected      string         `json:"selected"`
+}
+
+// ConfigDir returns the platform's config directory for the installer
+// itself, e.g. ~/.config/openrq-installer on Linux.
+func ConfigDir() (string, error) {
+	currentUser, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	switch runtime.GOOS {
+	case "windows":
+		return fmt.Sprintf("%s/AppData/Roaming/openrq-installer", currentUser.HomeDir), nil
+	case "darwin":
+		return fmt.Sprintf("%s/Library/Application Support/openrq-installer", currentUser.HomeDir), nil
+	default:
+		return fmt.Sprintf("%s/.config/openrq-installer", currentUser.HomeDir), nil
+	}
+}
+
+// path returns the full path to installations.json.
+func path() (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return dir + "/" + fileName, nil
+}
+
+// Load reads the registry from disk, returning an empty Registry (not an
+// error) if it doesn't exist yet.
+func Load() (*Registry, error) {
+	file, err := path()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return &Registry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var reg Registry
+	if err := json.Unmarshal(data, &reg); err != nil {
+		return nil, err
+	}
+	return &reg, nil
+}
+
+// Save writes the registry to disk, creating its config directory if
+// needed.
+func (r *Registry) Save() error {
+	dir, err := ConfigDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	file, err := path()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0600)
+}
+
+// Add registers inst, selecting it if no installation is currently
+// selected.
+func (r *Registry) Add(inst Installation) {
+	r.Installations = append(r.Installations, inst)
+	if r.Selected == "" {
+		r.Selected = inst.Path
+	}
+}
+
+// Remove drops the installation at path, clearing Selected if it pointed
+// there.
+func (r *Registry) Remove(installPath string) {
+	for i, inst := range r.Installations {
+		if inst.Path == installPath {
+			r.Installations = append(r.Installations[:i], r.Installations[i+1:]...)
+			break
+		}
+	}
+	if r.Selected == installPath {
+		r.Selected = ""
+	}
+}
+
+// HasComponent reports whether component (e.g. "service", "shortcut") was
+// selected when this installation was set up.
+func (i *Installation) HasComponent(component string) bool {
+	for _, c := range i.Components {
+		if c == component {
+			return true
+		}
+	}
+	return false
+}
+
+// Find returns the installation at path, or nil if there isn't one.
+func (r *Registry) Find(installPath string) *Installation {
+	for i := range r.Installations {
+		if r.Installations[i].Path == installPath {
+			return &r.Installations[i]
+		}
+	}
+	return nil
+}
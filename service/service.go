@@ -0,0 +1,35 @@
+// Package service registers OpenRQ to auto-start using whatever mechanism
+// is native to the current platform: a systemd user unit on Linux, a
+// launchd agent on macOS, or a Windows Service on Windows.
+//
+// Since the installer supports multiple side-by-side installs, every
+// registered service/unit/agent is scoped to the install it belongs to via
+// Name - callers must not share a single identity across installations.
+package service
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+)
+
+// Name derives a stable, per-installation service identity from
+// installPath, so two side-by-side installs never collide over (or
+// clobber) the same systemd unit/launchd agent/Windows service.
+func Name(installPath string) string {
+	sum := sha1.Sum([]byte(installPath))
+	return fmt.Sprintf("openrq-%s", hex.EncodeToString(sum[:])[:8])
+}
+
+// Install registers execPath to auto-start as a service for the current
+// user, under the given name (see Name). execPath should be
+// GetInstallPath()+GetExecutableName(), not the installer's own path.
+func Install(name, execPath string) error {
+	return install(name, execPath)
+}
+
+// Uninstall removes whatever Install registered under name. It's safe to
+// call even if Install was never run for that name.
+func Uninstall(name string) error {
+	return uninstall(name)
+}
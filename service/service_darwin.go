@@ -0,0 +1,69 @@
+package service
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/user"
+)
+
+// label derives the launchd agent label (e.g. "com.openrq.openrq-1a2b3c4d")
+// from the per-installation service name.
+func label(name string) string {
+	return fmt.Sprintf("com.openrq.%s", name)
+}
+
+// plistPath returns ~/Library/LaunchAgents/<label>.plist.
+func plistPath(name string) (string, error) {
+	currentUser, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/Library/LaunchAgents/%s.plist", currentUser.HomeDir, label(name)), nil
+}
+
+func install(name, execPath string) error {
+	path, err := plistPath(name)
+	if err != nil {
+		return err
+	}
+
+	plist := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>%s</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>%s</string>
+	</array>
+	<key>RunAtLoad</key>
+	<true/>
+</dict>
+</plist>
+`, label(name), execPath)
+	if err := ioutil.WriteFile(path, []byte(plist), 0600); err != nil {
+		return err
+	}
+
+	return exec.Command("launchctl", "load", path).Run()
+}
+
+func uninstall(name string) error {
+	path, err := plistPath(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	if err := exec.Command("launchctl", "unload", path).Run(); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
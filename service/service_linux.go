@@ -0,0 +1,70 @@
+package service
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/user"
+)
+
+// unitDir returns ~/.config/systemd/user, resolved the same way for both
+// the directory we create and the file we write into it.
+func unitDir() (string, error) {
+	currentUser, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/.config/systemd/user", currentUser.HomeDir), nil
+}
+
+// unitPath returns ~/.config/systemd/user/<name>.service.
+func unitPath(name string) (string, error) {
+	dir, err := unitDir()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%s.service", dir, name), nil
+}
+
+func install(name, execPath string) error {
+	// Resolve the directory once and reuse it for both MkdirAll and the
+	// WriteFile below (via unitPath), rather than letting os.Getenv("HOME")
+	// and user.Current().HomeDir disagree under e.g. sudo
+	dir, err := unitDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	path, err := unitPath(name)
+	if err != nil {
+		return err
+	}
+	unit := fmt.Sprintf("[Unit]\nDescription=%s\n\n[Service]\nExecStart=%s\n\n[Install]\nWantedBy=default.target\n",
+		name, execPath)
+	if err := ioutil.WriteFile(path, []byte(unit), 0600); err != nil {
+		return err
+	}
+
+	return exec.Command("systemctl", "--user", "enable", name+".service").Run()
+}
+
+func uninstall(name string) error {
+	path, err := unitPath(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	if err := exec.Command("systemctl", "--user", "disable", name+".service").Run(); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"fyne.io/fyne"
+	"fyne.io/fyne/dialog"
+	"fyne.io/fyne/layout"
+	"fyne.io/fyne/widget"
+
+	"github.com/kraxarn/OpenRQinstaller/registry"
+	"github.com/kraxarn/OpenRQinstaller/release"
+	"github.com/kraxarn/OpenRQinstaller/service"
+)
+
+// wizardStep identifies each page of the install wizard, in order.
+type wizardStep int
+
+const (
+	stepWelcome wizardStep = iota
+	stepLicense
+	stepInstallPath
+	stepComponents
+	stepProgress
+	stepFinish
+	stepCount
+)
+
+// Wizard drives the install UI as a sequence of steps with Back/Next/Cancel
+// navigation, replacing the old single-page layout.
+type Wizard struct {
+	parent fyne.Window
+	reg    *registry.Registry
+	// onFinished is called once the wizard's Finish step is dismissed, so
+	// the caller can refresh whatever list of installations it's showing.
+	onFinished func()
+
+	step  wizardStep
+	stack *fyne.Container
+
+	backButton, nextButton, cancelButton *widget.Button
+
+	// Collected across steps
+	language          string
+	licenseAccepted   bool
+	installPath       string
+	createShortcut    bool
+	createStartMenu   bool
+	registerFileAssoc bool
+	installService    bool
+	launchOnFinish    bool
+
+	progress *widget.ProgressBar
+	status   *widget.Label
+}
+
+// NewWizard builds a Wizard ready to show its first step. defaultPath, if
+// non-empty, pre-fills the install location step (used for "Add new
+// installation"); otherwise the platform default is used.
+func NewWizard(parent fyne.Window, reg *registry.Registry, defaultPath string, onFinished func()) *Wizard {
+	if defaultPath == "" {
+		defaultPath = GetInstallPath("")
+	}
+	w := &Wizard{
+		parent:          parent,
+		reg:             reg,
+		onFinished:      onFinished,
+		installPath:     defaultPath,
+		createShortcut:  true,
+		createStartMenu: true,
+		progress:        widget.NewProgressBar(),
+		status:          widget.NewLabel("Waiting..."),
+	}
+	w.stack = fyne.NewContainerWithLayout(layout.NewMaxLayout())
+	w.backButton = widget.NewButton("Back", func() { w.show(w.step - 1) })
+	w.nextButton = widget.NewButton("Next", func() { w.advance() })
+	w.cancelButton = widget.NewButton("Cancel", func() { w.cancel() })
+	w.show(stepWelcome)
+	return w
+}
+
+// Container returns the full wizard layout, including navigation buttons.
+func (w *Wizard) Container() fyne.CanvasObject {
+	nav := fyne.NewContainerWithLayout(layout.NewGridLayout(3), w.cancelButton, w.backButton, w.nextButton)
+	return widget.NewVBox(w.stack, nav)
+}
+
+// cancel confirms with the user, then backs out of the wizard entirely:
+// back to the installations list if there are any to show, or otherwise
+// closes the window, since GetLayout would have nothing to show but this
+// same wizard again.
+func (w *Wizard) cancel() {
+	dialog.ShowConfirm("Cancel installation?", "Are you sure you want to cancel?", func(ok bool) {
+		if !ok {
+			return
+		}
+		if w.reg != nil && len(w.reg.Installations) > 0 {
+			if w.onFinished != nil {
+				w.onFinished()
+			}
+			return
+		}
+		w.parent.Close()
+	}, w.parent)
+}
+
+// show swaps the visible step, rebuilding its contents and updating the
+// navigation buttons to match.
+func (w *Wizard) show(step wizardStep) {
+	if step < stepWelcome || step >= stepCount {
+		return
+	}
+	w.step = step
+
+	w.backButton.Enable()
+	if step == stepWelcome || step == stepProgress {
+		// Welcome has nowhere to go back to; Progress must not be
+		// escapable mid-install, or Back-then-Next would re-enter
+		// updateNextButton's stepProgress case and kick off a second,
+		// overlapping runInstall goroutine
+		w.backButton.Disable()
+	}
+
+	w.cancelButton.Enable()
+	if step == stepProgress {
+		// Same reasoning as backButton above: cancelling out from under a
+		// running runInstall would race its goroutine
+		w.cancelButton.Disable()
+	}
+
+	var content fyne.CanvasObject
+	switch step {
+	case stepWelcome:
+		content = w.buildWelcomeStep()
+	case stepLicense:
+		content = w.buildLicenseStep()
+	case stepInstallPath:
+		content = w.buildInstallPathStep()
+	case stepComponents:
+		content = w.buildComponentsStep()
+	case stepProgress:
+		content = w.buildProgressStep()
+	case stepFinish:
+		content = w.buildFinishStep()
+	}
+
+	w.stack.Objects = []fyne.CanvasObject{content}
+	w.updateNextButton()
+	w.stack.Refresh()
+}
+
+// updateNextButton sets the Next button's label/enabled state for the
+// current step, and kicks off the install once we reach the progress step.
+func (w *Wizard) updateNextButton() {
+	switch w.step {
+	case stepLicense:
+		w.nextButton.SetText("Next")
+		if w.licenseAccepted {
+			w.nextButton.Enable()
+		} else {
+			w.nextButton.Disable()
+		}
+	case stepProgress:
+		w.nextButton.SetText("Next")
+		w.nextButton.Disable()
+		go w.runInstall()
+	case stepFinish:
+		w.nextButton.SetText("Finish")
+		w.nextButton.Enable()
+	default:
+		w.nextButton.SetText("Next")
+		w.nextButton.Enable()
+	}
+}
+
+// advance moves to the next step, or finishes the wizard from the last one.
+func (w *Wizard) advance() {
+	if w.step == stepFinish {
+		if w.launchOnFinish {
+			_ = exec.Command(GetInstallPath(w.installPath) + GetExecutableName()).Start()
+		}
+		if w.onFinished != nil {
+			w.onFinished()
+		}
+		return
+	}
+	w.show(w.step + 1)
+}
+
+func (w *Wizard) buildWelcomeStep() fyne.CanvasObject {
+	languages := widget.NewSelect([]string{"English"}, func(selected string) {
+		w.language = selected
+	})
+	languages.SetSelected("English")
+	return widget.NewVBox(
+		widget.NewGroup(fmt.Sprintf("Welcome to the %s installer!", appName),
+			widget.NewLabel("This wizard will guide you through the installation."),
+			widget.NewLabel("Language:"),
+			languages,
+		),
+	)
+}
+
+func (w *Wizard) buildLicenseStep() fyne.CanvasObject {
+	text := widget.NewScrollContainer(widget.NewLabel(licenses))
+	accept := widget.NewCheck("I accept the license agreement", func(checked bool) {
+		w.licenseAccepted = checked
+		w.updateNextButton()
+	})
+	accept.SetChecked(w.licenseAccepted)
+	return widget.NewVBox(
+		widget.NewGroup("License agreement", text, accept),
+	)
+}
+
+func (w *Wizard) buildInstallPathStep() fyne.CanvasObject {
+	path := widget.NewEntry()
+	path.SetText(w.installPath)
+	path.OnChanged = func(text string) {
+		w.installPath = text
+	}
+	browse := widget.NewButton("Browse...", func() {
+		dialog.ShowFolderOpen(func(uri fyne.ListableURI, err error) {
+			if err != nil || uri == nil {
+				return
+			}
+			// uri.String() includes the "file://" scheme; GetInstallPath
+			// and friends expect a plain filesystem path
+			selected := strings.TrimPrefix(uri.String(), uri.Scheme()+"://")
+			path.SetText(selected)
+			w.installPath = selected
+		}, w.parent)
+	})
+	return widget.NewVBox(
+		widget.NewGroup("Install location",
+			widget.NewLabel("Choose the folder to install "+appName+" to:"),
+			fyne.NewContainerWithLayout(layout.NewBorderLayout(nil, nil, nil, browse), browse, path),
+		),
+	)
+}
+
+func (w *Wizard) buildComponentsStep() fyne.CanvasObject {
+	shortcut := widget.NewCheck("Create desktop shortcut", func(checked bool) {
+		w.createShortcut = checked
+	})
+	shortcut.SetChecked(w.createShortcut)
+
+	startMenu := widget.NewCheck("Create start-menu entry", func(checked bool) {
+		w.createStartMenu = checked
+	})
+	startMenu.SetChecked(w.createStartMenu)
+
+	fileAssoc := widget.NewCheck("Register file associations", func(checked bool) {
+		w.registerFileAssoc = checked
+	})
+	fileAssoc.SetChecked(w.registerFileAssoc)
+
+	serviceCheck := widget.NewCheck("Install as service (start automatically)", func(checked bool) {
+		w.installService = checked
+	})
+	serviceCheck.SetChecked(w.installService)
+
+	return widget.NewVBox(
+		widget.NewGroup("Components", shortcut, startMenu, fileAssoc, serviceCheck),
+	)
+}
+
+func (w *Wizard) buildProgressStep() fyne.CanvasObject {
+	return widget.NewVBox(
+		widget.NewGroup("Installing", w.status, w.progress),
+	)
+}
+
+func (w *Wizard) buildFinishStep() fyne.CanvasObject {
+	launch := widget.NewCheck(fmt.Sprintf("Launch %s now", appName), func(checked bool) {
+		w.launchOnFinish = checked
+	})
+	launch.SetChecked(w.launchOnFinish)
+	return widget.NewVBox(
+		widget.NewGroup("Setup complete", widget.NewLabel(w.status.Text), launch),
+	)
+}
+
+// runInstall performs the actual install/shortcut creation in the
+// background, then advances to the finish step once done.
+func (w *Wizard) runInstall() {
+	w.progress.SetValue(0)
+
+	var err error
+	if err = Install(w.installPath, w.progress, w.status); err == nil && (w.createShortcut || w.createStartMenu) {
+		err = CreateShortcut(w.installPath, w.createShortcut, w.createStartMenu)
+	}
+	if err == nil && w.registerFileAssoc {
+		w.status.SetText("Registering file associations...")
+		err = RegisterFileAssociations(w.installPath)
+	}
+	if err == nil && w.installService {
+		w.status.SetText("Registering service...")
+		installPath := GetInstallPath(w.installPath)
+		err = service.Install(service.Name(installPath), installPath+GetExecutableName())
+	}
+
+	if err != nil {
+		dialog.ShowError(err, w.parent)
+		w.status.SetText("Install failed")
+		return
+	}
+
+	if w.reg != nil {
+		w.recordInstallation()
+	}
+
+	w.progress.SetValue(1)
+	w.status.SetText("Installation successful!")
+	w.nextButton.Enable()
+	w.show(stepFinish)
+}
+
+// recordInstallation adds (or updates) this install in the registry once
+// it has completed successfully.
+func (w *Wizard) recordInstallation() {
+	installPath := GetInstallPath(w.installPath)
+
+	var components []string
+	if w.createShortcut {
+		components = append(components, "shortcut")
+	}
+	if w.createStartMenu {
+		components = append(components, "start-menu")
+	}
+	if w.registerFileAssoc {
+		components = append(components, "file-associations")
+	}
+	if w.installService {
+		components = append(components, "service")
+	}
+
+	version := ""
+	if installed, err := release.ReadInstalled(installPath); err == nil {
+		version = installed.Version
+	}
+
+	w.reg.Remove(installPath)
+	w.reg.Add(registry.Installation{
+		Path:        installPath,
+		Version:     version,
+		InstalledAt: time.Now().Format(time.RFC3339),
+		Components:  components,
+	})
+	if err := w.reg.Save(); err != nil {
+		dialog.ShowError(err, w.parent)
+	}
+}
@@ -0,0 +1,150 @@
+// Package xar implements just enough of Apple's xar archive format (as
+// used by .pkg installers) to walk its table of contents and read out each
+// payload file. It does not support writing xar archives or verifying the
+// archive checksum.
+package xar
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+)
+
+// headerSize is the fixed size of the xar header, in bytes.
+const headerSize = 28
+
+var magic = [4]byte{'x', 'a', 'r', '!'}
+
+// File is a single payload entry from the xar table of contents.
+type File struct {
+	Name     string
+	Offset   int64
+	Size     int64
+	Encoding string
+}
+
+// Reader walks the table of contents of a xar archive and lets callers
+// read each file out of the heap that follows it.
+type Reader struct {
+	r          io.ReaderAt
+	heapOffset int64
+	Files      []File
+}
+
+// header mirrors the 28-byte xar header layout.
+type header struct {
+	Magic               [4]byte
+	HeaderSize          uint16
+	Version             uint16
+	TOCLengthCompressed uint64
+	TOCLengthRaw        uint64
+	ChecksumAlg         uint32
+}
+
+// tocXML and fileXML mirror just the bits of the xar TOC XML schema that
+// we need: a tree of <file> entries, each with a name and, for regular
+// files, a <data> block describing where to find its bytes in the heap.
+type tocXML struct {
+	TOC struct {
+		Files []fileXML `xml:"file"`
+	} `xml:"toc"`
+}
+
+type fileXML struct {
+	Name  string    `xml:"name"`
+	Type  string    `xml:"type"`
+	Data  *dataXML  `xml:"data"`
+	Files []fileXML `xml:"file"`
+}
+
+type dataXML struct {
+	Offset   int64 `xml:"offset"`
+	Size     int64 `xml:"size"`
+	Encoding struct {
+		Style string `xml:"style,attr"`
+	} `xml:"encoding"`
+}
+
+// NewReader parses the xar header and table of contents from r, which must
+// span exactly size bytes of xar archive data.
+func NewReader(r io.ReaderAt, size int64) (*Reader, error) {
+	rawHeader := make([]byte, headerSize)
+	if _, err := r.ReadAt(rawHeader, 0); err != nil {
+		return nil, err
+	}
+
+	var h header
+	if err := binary.Read(bytes.NewReader(rawHeader), binary.BigEndian, &h); err != nil {
+		return nil, err
+	}
+	if h.Magic != magic {
+		return nil, fmt.Errorf("xar: bad magic %q", h.Magic)
+	}
+
+	compressedTOC := io.NewSectionReader(r, int64(h.HeaderSize), int64(h.TOCLengthCompressed))
+	zlibReader, err := zlib.NewReader(compressedTOC)
+	if err != nil {
+		return nil, err
+	}
+	defer zlibReader.Close()
+
+	tocBytes, err := ioutil.ReadAll(zlibReader)
+	if err != nil {
+		return nil, err
+	}
+
+	var toc tocXML
+	if err := xml.Unmarshal(tocBytes, &toc); err != nil {
+		return nil, err
+	}
+
+	reader := &Reader{
+		r:          r,
+		heapOffset: int64(h.HeaderSize) + int64(h.TOCLengthCompressed),
+	}
+	reader.Files = walk("", toc.TOC.Files)
+	return reader, nil
+}
+
+// walk flattens the (possibly nested, for directories) file tree from the
+// TOC into a flat list of payload files with their full relative path.
+func walk(prefix string, entries []fileXML) []File {
+	var files []File
+	for _, entry := range entries {
+		name := path.Join(prefix, entry.Name)
+		if entry.Type == "directory" {
+			files = append(files, walk(name, entry.Files)...)
+			continue
+		}
+		if entry.Data == nil {
+			continue
+		}
+		files = append(files, File{
+			Name:     name,
+			Offset:   entry.Data.Offset,
+			Size:     entry.Data.Size,
+			Encoding: entry.Data.Encoding.Style,
+		})
+	}
+	return files
+}
+
+// Open returns a reader over f's (decompressed) contents.
+func (r *Reader) Open(f File) (io.ReadCloser, error) {
+	section := io.NewSectionReader(r.r, r.heapOffset+f.Offset, f.Size)
+	switch f.Encoding {
+	case "application/x-gzip":
+		return gzip.NewReader(section)
+	case "application/x-bzip2":
+		return ioutil.NopCloser(bzip2.NewReader(section)), nil
+	default:
+		return ioutil.NopCloser(section), nil
+	}
+}